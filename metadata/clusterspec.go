@@ -0,0 +1,211 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/chemist/ksonnet/metadata/fsext"
+)
+
+const (
+	schemeFile    = "file"
+	schemeVersion = "version"
+	schemeHTTP    = "http"
+	schemeHTTPS   = "https"
+	schemeKubectl = "kubectl"
+
+	// swaggerSpecPathFmt is appended to swaggerMirror to resolve a `version:`
+	// cluster spec to the canonical swagger document for that release.
+	swaggerSpecPathFmt = "/kubernetes/kubernetes/%s/api/openapi-spec/swagger.json"
+)
+
+// swaggerMirror is the HTTPS host used to resolve `version:` cluster specs.
+// It is a var (rather than a const) so it can be pointed at a test server.
+var swaggerMirror = "https://raw.githubusercontent.com"
+
+// fetchSwaggerFromClusterFn fetches the OpenAPI spec from a live cluster via
+// `kubectl --context=<context> get --raw /openapi/v2`. It is a var so tests
+// can stub out the kubectl dependency.
+var fetchSwaggerFromClusterFn = fetchSwaggerFromCluster
+
+// ClusterSpec is the user-specified location of the OpenAPI spec ksonnet
+// uses to generate an environment's ksonnet-lib, expressed as a
+// `<scheme>:<source>` URI. Supported schemes are:
+//
+//   file:<path>          a swagger.json already on disk
+//   version:<version>    e.g. `version:v1.8.3`, resolved against swaggerMirror
+//   http(s)://<url>      fetched once, cached under the app's libDir, and reused on later resolves
+//   kubectl:<context>    fetched from a live cluster via `kubectl get --raw`
+type ClusterSpec struct {
+	scheme string
+	source string
+}
+
+// parseClusterSpec parses a cluster spec URI into a ClusterSpec.
+func parseClusterSpec(specFlag string, fs fsext.FS) (*ClusterSpec, error) {
+	parts := strings.SplitN(specFlag, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Could not parse cluster spec '%s'", specFlag)
+	}
+
+	scheme, source := parts[0], parts[1]
+	switch scheme {
+	case schemeFile, schemeVersion, schemeKubectl:
+		// source is already the part after the scheme.
+	case schemeHTTP, schemeHTTPS:
+		// Keep the scheme as part of the source; it's a complete URL.
+		source = specFlag
+	default:
+		return nil, fmt.Errorf("Unrecognized cluster spec scheme '%s'", scheme)
+	}
+
+	return &ClusterSpec{scheme: scheme, source: source}, nil
+}
+
+// resolve fetches the raw OpenAPI spec this ClusterSpec refers to, returning
+// the spec bytes alongside the Kubernetes version they describe. http(s)
+// specs are cached under cacheDir (the app's libDir) so that resolving the
+// same spec again doesn't re-fetch it; cacheDir may be empty to disable
+// caching, e.g. when no app root is available yet.
+func (s *ClusterSpec) resolve(fs fsext.FS, cacheDir string) (specData []byte, version string, err error) {
+	switch s.scheme {
+	case schemeFile:
+		specData, err = fs.ReadFile(s.source)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "Could not read cluster spec file '%s'", s.source)
+		}
+	case schemeVersion:
+		version = s.source
+		url := swaggerMirror + fmt.Sprintf(swaggerSpecPathFmt, s.source)
+		if specData, err = fetchSwaggerURL(url); err != nil {
+			return nil, "", err
+		}
+	case schemeHTTP, schemeHTTPS:
+		if specData, err = s.resolveCachedHTTP(fs, cacheDir); err != nil {
+			return nil, "", err
+		}
+	case schemeKubectl:
+		if specData, err = fetchSwaggerFromClusterFn(s.source); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", fmt.Errorf("Unrecognized cluster spec scheme '%s'", s.scheme)
+	}
+
+	if version == "" {
+		if version, err = parseSwaggerVersion(specData); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return specData, version, nil
+}
+
+// resolveCachedHTTP fetches s.source over HTTP(S), serving it from cacheDir
+// if it was already fetched once before. cacheDir may be empty, in which
+// case the spec is always fetched fresh.
+func (s *ClusterSpec) resolveCachedHTTP(fs fsext.FS, cacheDir string) ([]byte, error) {
+	if cacheDir == "" {
+		return fetchSwaggerURL(s.source)
+	}
+
+	cachePath := filepath.Join(cacheDir, swaggerCacheKey(s.source))
+
+	exists, err := fsext.Exists(fs, cachePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not stat cluster spec cache '%s'", cachePath)
+	} else if exists {
+		specData, err := fs.ReadFile(cachePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not read cached cluster spec '%s'", cachePath)
+		}
+		return specData, nil
+	}
+
+	specData, err := fetchSwaggerURL(s.source)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fs.MkdirAll(cacheDir, defaultFolderPermissions); err != nil {
+		return nil, errors.Wrapf(err, "Could not create cluster spec cache directory '%s'", cacheDir)
+	}
+	if err := fs.WriteFile(cachePath, specData, defaultFilePermissions); err != nil {
+		return nil, errors.Wrapf(err, "Could not cache cluster spec to '%s'", cachePath)
+	}
+
+	return specData, nil
+}
+
+// swaggerCacheKey derives the cache filename for an http(s) cluster spec
+// source, so that two different URLs never collide.
+func swaggerCacheKey(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return fmt.Sprintf("%x.json", sum)
+}
+
+// fetchSwaggerURL retrieves a swagger document over HTTP(S).
+func fetchSwaggerURL(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not fetch cluster spec from '%s'", url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not fetch cluster spec from '%s': got HTTP %d", url, resp.StatusCode)
+	}
+
+	specData, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not read cluster spec from '%s'", url)
+	}
+
+	return specData, nil
+}
+
+// fetchSwaggerFromCluster shells out to kubectl to retrieve the swagger
+// document served by a live cluster's API server.
+func fetchSwaggerFromCluster(context string) ([]byte, error) {
+	args := []string{"get", "--raw", "/openapi/v2"}
+	if context != "" {
+		args = append([]string{fmt.Sprintf("--context=%s", context)}, args...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("kubectl", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "Could not fetch cluster spec via kubectl: %s", stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// parseSwaggerVersion extracts the Kubernetes version a swagger document
+// describes from its `info.version` field.
+func parseSwaggerVersion(specData []byte) (string, error) {
+	var swagger struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+
+	if err := json.Unmarshal(specData, &swagger); err != nil {
+		return "", errors.Wrap(err, "Could not parse cluster spec")
+	} else if swagger.Info.Version == "" {
+		return "", errors.New("Cluster spec did not specify a Kubernetes version")
+	}
+
+	return swagger.Info.Version, nil
+}
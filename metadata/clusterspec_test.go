@@ -0,0 +1,114 @@
+package metadata
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/chemist/ksonnet/metadata/fsext"
+)
+
+func TestParseClusterSpec(t *testing.T) {
+	swaggerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(blankSwaggerData))
+	}))
+	defer swaggerServer.Close()
+
+	origMirror := swaggerMirror
+	swaggerMirror = swaggerServer.URL
+	defer func() { swaggerMirror = origMirror }()
+
+	origFetchFromCluster := fetchSwaggerFromClusterFn
+	fetchSwaggerFromClusterFn = func(context string) ([]byte, error) {
+		return []byte(blankSwaggerData), nil
+	}
+	defer func() { fetchSwaggerFromClusterFn = origFetchFromCluster }()
+
+	rawFS := afero.NewMemMapFs()
+	afero.WriteFile(rawFS, blankSwagger, []byte(blankSwaggerData), 0644)
+	fs := fsext.NewAferoFS(rawFS)
+
+	tests := []struct {
+		name string
+		uri  string
+	}{
+		{"file", fmt.Sprintf("file:%s", blankSwagger)},
+		{"version", "version:v1.7.0"},
+		{"http", swaggerServer.URL},
+		{"kubectl", "kubectl:my-context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := parseClusterSpec(tt.uri, fs)
+			if err != nil {
+				t.Fatalf("Failed to parse cluster spec '%s': %v", tt.uri, err)
+			}
+
+			specData, version, err := spec.resolve(fs, "/app/lib")
+			if err != nil {
+				t.Fatalf("Failed to resolve cluster spec '%s': %v", tt.uri, err)
+			}
+			if string(specData) != blankSwaggerData {
+				t.Fatalf("Expected resolved spec data to match fixture, got '%s'", specData)
+			}
+			if version != "v1.7.0" {
+				t.Fatalf("Expected resolved version 'v1.7.0', got '%s'", version)
+			}
+		})
+	}
+}
+
+func TestResolveCachesHTTPSpec(t *testing.T) {
+	requests := 0
+	swaggerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(blankSwaggerData))
+	}))
+	defer swaggerServer.Close()
+
+	fs := fsext.NewAferoFS(afero.NewMemMapFs())
+	cacheDir := "/app/lib"
+
+	spec, err := parseClusterSpec(swaggerServer.URL, fs)
+	if err != nil {
+		t.Fatalf("Failed to parse cluster spec: %v", err)
+	}
+
+	if _, _, err := spec.resolve(fs, cacheDir); err != nil {
+		t.Fatalf("Failed to resolve cluster spec: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("Expected 1 request to the swagger server, got %d", requests)
+	}
+
+	specData, version, err := spec.resolve(fs, cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to resolve cached cluster spec: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("Expected cached resolve not to hit the swagger server again, got %d requests", requests)
+	}
+	if string(specData) != blankSwaggerData {
+		t.Fatalf("Expected cached spec data to match fixture, got '%s'", specData)
+	}
+	if version != "v1.7.0" {
+		t.Fatalf("Expected cached resolve to still report version 'v1.7.0', got '%s'", version)
+	}
+
+	if exists, err := fsext.DirExists(fs, cacheDir); err != nil {
+		t.Fatalf("Failed to stat cache directory '%s': %v", cacheDir, err)
+	} else if !exists {
+		t.Fatalf("Expected cache directory '%s' to be created", cacheDir)
+	}
+}
+
+func TestParseClusterSpecUnrecognizedScheme(t *testing.T) {
+	fs := fsext.NewAferoFS(afero.NewMemMapFs())
+	if _, err := parseClusterSpec("ftp:example.com/swagger.json", fs); err == nil {
+		t.Fatalf("Expected unrecognized scheme to fail to parse")
+	}
+}
@@ -0,0 +1,201 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/chemist/ksonnet/metadata/fsext"
+	"github.com/chemist/ksonnet/metadata/machinery"
+)
+
+const environmentsSpecFilename = "spec.json"
+
+// Environment describes a single ksonnet deploy target: the cluster it
+// points at, and the Kubernetes version its ksonnet-lib was generated from.
+type Environment struct {
+	Name       string `json:"-"`
+	Server     string `json:"server"`
+	Namespace  string `json:"namespace"`
+	K8sVersion string `json:"k8sVersion"`
+}
+
+// environmentSpecs is the on-disk shape of environments/spec.json: a map of
+// environment name to its recorded cluster coordinates.
+type environmentSpecs map[string]*Environment
+
+// CreateEnvironment scaffolds a new environment named name, recording
+// server and namespace and generating its ksonnet-lib from spec.
+//
+// The environment's directory, its three generated files, and the updated
+// environments/spec.json are all staged and committed through a single
+// Scaffold.Execute call, so a failure partway through (e.g. the swagger
+// document writes but ksonnet-lib fails to write) leaves neither a
+// half-written environment directory nor a spec.json entry pointing at one.
+// ksonnet-lib is generated before anything is written.
+func (m *manager) CreateEnvironment(name, server, namespace string, spec *ClusterSpec) error {
+	specs, err := m.readEnvironmentSpecs()
+	if err != nil {
+		return err
+	} else if _, exists := specs[name]; exists {
+		return fmt.Errorf("Environment '%s' already exists", name)
+	}
+
+	templates, version, err := m.environmentTemplates(name, spec)
+	if err != nil {
+		return err
+	}
+
+	specs[name] = &Environment{Server: server, Namespace: namespace, K8sVersion: version}
+	specsData, err := serializeEnvironmentSpecs(specs)
+	if err != nil {
+		return err
+	}
+	templates = append(templates, machinery.Template{
+		Path:           string(m.environmentSpecsPath()),
+		Contents:       specsData,
+		IfExistsAction: machinery.IfExistsOverwrite,
+	})
+
+	scaffold := machinery.Scaffold{}
+	if err := scaffold.Execute(m.fs, string(m.rootPath), templates...); err != nil {
+		return errors.Wrapf(err, "Could not scaffold environment '%s'", name)
+	}
+
+	return nil
+}
+
+// environmentTemplates builds the Scaffold templates for a single
+// environment's directory under environmentsDir: its swagger document,
+// generated ksonnet-lib, and extensions library. It returns the templates
+// alongside the Kubernetes version spec resolved to.
+func (m *manager) environmentTemplates(name string, spec *ClusterSpec) ([]machinery.Template, string, error) {
+	cacheDir := string(appendToAbsPath(m.rootPath, libDir))
+	specData, version, err := spec.resolve(m.fs, cacheDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	k8sLib, extensionsLib, err := generateLibs(specData, version)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "Could not generate ksonnet-lib for environment '%s'", name)
+	}
+
+	envPath := appendToAbsPath(appendToAbsPath(m.rootPath, environmentsDir), name)
+	templates := []machinery.Template{
+		{Path: string(envPath), IsDir: true, IfExistsAction: machinery.IfExistsError},
+		{Path: string(appendToAbsPath(envPath, schemaFilename)), Contents: specData, IfExistsAction: machinery.IfExistsError},
+		{Path: string(appendToAbsPath(envPath, k8sLibFilename)), Contents: []byte(k8sLib), IfExistsAction: machinery.IfExistsError},
+		{Path: string(appendToAbsPath(envPath, extensionsLibFilename)), Contents: []byte(extensionsLib), IfExistsAction: machinery.IfExistsError},
+	}
+
+	return templates, version, nil
+}
+
+// DeleteEnvironment removes the environment named name: its directory under
+// environmentsDir and its entry in environments/spec.json.
+func (m *manager) DeleteEnvironment(name string) error {
+	specs, err := m.readEnvironmentSpecs()
+	if err != nil {
+		return err
+	} else if _, exists := specs[name]; !exists {
+		return fmt.Errorf("Environment '%s' does not exist", name)
+	}
+
+	envPath := appendToAbsPath(appendToAbsPath(m.rootPath, environmentsDir), name)
+	if err := m.fs.RemoveAll(string(envPath)); err != nil {
+		return errors.Wrapf(err, "Could not remove environment directory '%s'", envPath)
+	}
+
+	delete(specs, name)
+	return m.writeEnvironmentSpecs(specs)
+}
+
+// ListEnvironments returns every environment recorded in
+// environments/spec.json, sorted by name.
+func (m *manager) ListEnvironments() ([]Environment, error) {
+	specs, err := m.readEnvironmentSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	envs := make([]Environment, 0, len(specs))
+	for name, env := range specs {
+		envs = append(envs, Environment{Name: name, Server: env.Server, Namespace: env.Namespace, K8sVersion: env.K8sVersion})
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+
+	return envs, nil
+}
+
+// SetCurrentEnvironment records name as the environment ksonnet commands
+// operate against by default.
+func (m *manager) SetCurrentEnvironment(name string) error {
+	specs, err := m.readEnvironmentSpecs()
+	if err != nil {
+		return err
+	} else if _, exists := specs[name]; !exists {
+		return fmt.Errorf("Environment '%s' does not exist", name)
+	}
+
+	currentPath := appendToAbsPath(appendToAbsPath(m.rootPath, ksonnetDir), currentEnvironmentFilename)
+	if err := m.fs.WriteFile(string(currentPath), []byte(name), defaultFilePermissions); err != nil {
+		return errors.Wrapf(err, "Could not write '%s'", currentPath)
+	}
+
+	return nil
+}
+
+func (m *manager) environmentSpecsPath() AbsPath {
+	return appendToAbsPath(appendToAbsPath(m.rootPath, environmentsDir), environmentsSpecFilename)
+}
+
+func (m *manager) readEnvironmentSpecs() (environmentSpecs, error) {
+	specsPath := m.environmentSpecsPath()
+
+	exists, err := fsext.Exists(m.fs, string(specsPath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not stat '%s'", specsPath)
+	} else if !exists {
+		return environmentSpecs{}, nil
+	}
+
+	data, err := m.fs.ReadFile(string(specsPath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not read '%s'", specsPath)
+	}
+
+	specs := environmentSpecs{}
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse '%s'", specsPath)
+	}
+
+	return specs, nil
+}
+
+func (m *manager) writeEnvironmentSpecs(specs environmentSpecs) error {
+	data, err := serializeEnvironmentSpecs(specs)
+	if err != nil {
+		return err
+	}
+
+	specsPath := m.environmentSpecsPath()
+	if err := m.fs.WriteFile(string(specsPath), data, defaultFilePermissions); err != nil {
+		return errors.Wrapf(err, "Could not write '%s'", specsPath)
+	}
+
+	return nil
+}
+
+// serializeEnvironmentSpecs renders specs to the JSON shape written to
+// environments/spec.json.
+func serializeEnvironmentSpecs(specs environmentSpecs) ([]byte, error) {
+	data, err := json.MarshalIndent(specs, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not serialize environment specs")
+	}
+
+	return data, nil
+}
@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	stagingSwagger     = "/stagingSwagger.json"
+	stagingSwaggerData = `{
+  "swagger": "2.0",
+  "info": {
+   "title": "Kubernetes",
+   "version": "v1.8.0"
+  },
+  "paths": {
+  },
+  "definitions": {
+  }
+}`
+
+	prodSwagger     = "/prodSwagger.json"
+	prodSwaggerData = `{
+  "swagger": "2.0",
+  "info": {
+   "title": "Kubernetes",
+   "version": "v1.9.0"
+  },
+  "paths": {
+  },
+  "definitions": {
+  }
+}`
+)
+
+func init() {
+	afero.WriteFile(testFS, stagingSwagger, []byte(stagingSwaggerData), os.ModePerm)
+	afero.WriteFile(testFS, prodSwagger, []byte(prodSwaggerData), os.ModePerm)
+}
+
+func TestMultiEnvironment(t *testing.T) {
+	envs := []struct {
+		name      string
+		server    string
+		namespace string
+		specURI   string
+		version   string
+	}{
+		{"dev", "https://dev.example.com", "default", fmt.Sprintf("file:%s", blankSwagger), "v1.7.0"},
+		{"staging", "https://staging.example.com", "default", fmt.Sprintf("file:%s", stagingSwagger), "v1.8.0"},
+		{"prod", "https://prod.example.com", "default", fmt.Sprintf("file:%s", prodSwagger), "v1.9.0"},
+	}
+
+	defaultSpec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testMetadataFS)
+	if err != nil {
+		t.Fatalf("Failed to parse cluster spec: %v", err)
+	}
+
+	appPath := AbsPath("/multiEnv")
+	m, err := initManager(appPath, defaultSpec, testMetadataFS)
+	if err != nil {
+		t.Fatalf("Failed to init cluster spec: %v", err)
+	}
+
+	for _, e := range envs {
+		spec, err := parseClusterSpec(e.specURI, testMetadataFS)
+		if err != nil {
+			t.Fatalf("Failed to parse cluster spec '%s': %v", e.specURI, err)
+		}
+
+		if err := m.CreateEnvironment(e.name, e.server, e.namespace, spec); err != nil {
+			t.Fatalf("Failed to create environment '%s': %v", e.name, err)
+		}
+	}
+
+	list, err := m.ListEnvironments()
+	if err != nil {
+		t.Fatalf("Failed to list environments: %v", err)
+	} else if len(list) != 4 {
+		// defaultEnvName (created by initManager) plus the three above.
+		t.Fatalf("Expected 4 environments, got %d: %v", len(list), list)
+	}
+
+	versionsByName := make(map[string]string)
+	for _, env := range list {
+		versionsByName[env.Name] = env.K8sVersion
+	}
+
+	swaggerPaths := make(map[string]bool)
+	swaggerContents := make(map[string]bool)
+	for _, e := range envs {
+		if versionsByName[e.name] != e.version {
+			t.Fatalf("Expected environment '%s' to report K8sVersion '%s', got '%s'", e.name, e.version, versionsByName[e.name])
+		}
+
+		envPath := appendToAbsPath(appendToAbsPath(appPath, environmentsDir), e.name)
+		schemaPath := appendToAbsPath(envPath, schemaFilename)
+		if swaggerPaths[string(schemaPath)] {
+			t.Fatalf("Duplicate swagger path '%s'", schemaPath)
+		}
+		swaggerPaths[string(schemaPath)] = true
+
+		data, err := afero.ReadFile(testFS, string(schemaPath))
+		if err != nil {
+			t.Fatalf("Expected swagger file at '%s' to exist: %v", schemaPath, err)
+		}
+		if swaggerContents[string(data)] {
+			t.Fatalf("Expected per-env swagger contents to be distinct, but '%s' duplicates an earlier environment", schemaPath)
+		}
+		swaggerContents[string(data)] = true
+	}
+
+	if err := m.SetCurrentEnvironment("staging"); err != nil {
+		t.Fatalf("Failed to set current environment: %v", err)
+	}
+
+	if err := m.DeleteEnvironment("dev"); err != nil {
+		t.Fatalf("Failed to delete environment 'dev': %v", err)
+	}
+
+	devPath := appendToAbsPath(appendToAbsPath(appPath, environmentsDir), "dev")
+	if exists, err := afero.DirExists(testFS, string(devPath)); err != nil {
+		t.Fatalf("Failed to stat '%s': %v", devPath, err)
+	} else if exists {
+		t.Fatalf("Expected environment directory '%s' to be removed", devPath)
+	}
+
+	stagingPath := appendToAbsPath(appendToAbsPath(appPath, environmentsDir), "staging")
+	if exists, err := afero.DirExists(testFS, string(stagingPath)); err != nil {
+		t.Fatalf("Failed to stat '%s': %v", stagingPath, err)
+	} else if !exists {
+		t.Fatalf("Expected environment directory '%s' to still exist", stagingPath)
+	}
+
+	list, err = m.ListEnvironments()
+	if err != nil {
+		t.Fatalf("Failed to list environments: %v", err)
+	} else if len(list) != 3 {
+		t.Fatalf("Expected 3 environments after delete, got %d: %v", len(list), list)
+	}
+}
@@ -0,0 +1,58 @@
+package fsext
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// AferoFS adapts an afero.Fs to the FS interface.
+type AferoFS struct {
+	fs afero.Fs
+}
+
+// NewAferoFS wraps fs as an FS.
+func NewAferoFS(fs afero.Fs) *AferoFS {
+	return &AferoFS{fs: fs}
+}
+
+// ReadFile implements FS.
+func (a *AferoFS) ReadFile(path string) ([]byte, error) {
+	return afero.ReadFile(a.fs, path)
+}
+
+// WriteFile implements FS.
+func (a *AferoFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return afero.WriteFile(a.fs, path, data, perm)
+}
+
+// Stat implements FS.
+func (a *AferoFS) Stat(path string) (os.FileInfo, error) {
+	return a.fs.Stat(path)
+}
+
+// MkdirAll implements FS.
+func (a *AferoFS) MkdirAll(path string, perm os.FileMode) error {
+	return a.fs.MkdirAll(path, perm)
+}
+
+// RemoveAll implements FS.
+func (a *AferoFS) RemoveAll(path string) error {
+	return a.fs.RemoveAll(path)
+}
+
+// Rename implements FS.
+func (a *AferoFS) Rename(oldpath, newpath string) error {
+	return a.fs.Rename(oldpath, newpath)
+}
+
+// Walk implements FS.
+func (a *AferoFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	return afero.Walk(a.fs, root, walkFn)
+}
+
+// OpenFile implements FS.
+func (a *AferoFS) OpenFile(path string, flag int, perm os.FileMode) (File, error) {
+	return a.fs.OpenFile(path, flag, perm)
+}
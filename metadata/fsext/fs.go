@@ -0,0 +1,68 @@
+// Package fsext abstracts the filesystem operations metadata needs behind a
+// narrow interface, so that afero-backed, os-backed, and composed
+// (overlaid) filesystems can all be used interchangeably.
+package fsext
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// File is the subset of *os.File behavior callers need once a file has been
+// opened.
+type File interface {
+	io.ReadWriteCloser
+}
+
+// FS is the filesystem surface the metadata package depends on.
+// Implementations exist for afero.Fs (AferoFS), the local disk (OsFS), and
+// for composing two FSes together (Overlay).
+type FS interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	Stat(path string) (os.FileInfo, error)
+	MkdirAll(path string, perm os.FileMode) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	Walk(root string, walkFn filepath.WalkFunc) error
+	OpenFile(path string, flag int, perm os.FileMode) (File, error)
+}
+
+// Exists reports whether path exists on fs.
+func Exists(fs FS, path string) (bool, error) {
+	_, err := fs.Stat(path)
+	if err == nil {
+		return true, nil
+	} else if isNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// DirExists reports whether path exists on fs and is a directory.
+func DirExists(fs FS, path string) (bool, error) {
+	info, err := fs.Stat(path)
+	if err == nil {
+		return info.IsDir(), nil
+	} else if isNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// IsDir reports whether path exists on fs and is a directory, returning an
+// error if path does not exist at all.
+func IsDir(fs FS, path string) (bool, error) {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func isNotExist(err error) bool {
+	return err != nil && os.IsNotExist(errors.Cause(err))
+}
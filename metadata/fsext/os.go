@@ -0,0 +1,50 @@
+package fsext
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// OsFS is an FS backed directly by the local filesystem.
+type OsFS struct{}
+
+// ReadFile implements FS.
+func (OsFS) ReadFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+// WriteFile implements FS.
+func (OsFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(path, data, perm)
+}
+
+// Stat implements FS.
+func (OsFS) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
+// MkdirAll implements FS.
+func (OsFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// RemoveAll implements FS.
+func (OsFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// Rename implements FS.
+func (OsFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Walk implements FS.
+func (OsFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	return filepath.Walk(root, walkFn)
+}
+
+// OpenFile implements FS.
+func (OsFS) OpenFile(path string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(path, flag, perm)
+}
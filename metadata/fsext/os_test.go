@@ -0,0 +1,69 @@
+package fsext
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOsFSSmoke(t *testing.T) {
+	fs := OsFS{}
+	dir := t.TempDir()
+
+	sub := filepath.Join(dir, "a", "b")
+	if err := fs.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Failed to MkdirAll '%s': %v", sub, err)
+	}
+	if info, err := fs.Stat(sub); err != nil {
+		t.Fatalf("Failed to stat '%s': %v", sub, err)
+	} else if !info.IsDir() {
+		t.Fatalf("Expected '%s' to be a directory", sub)
+	}
+
+	path := filepath.Join(sub, "f.txt")
+	if err := fs.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to WriteFile '%s': %v", path, err)
+	}
+
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to ReadFile '%s': %v", path, err)
+	} else if string(data) != "hello" {
+		t.Fatalf("Expected to read 'hello', got '%s'", data)
+	}
+
+	renamed := filepath.Join(sub, "g.txt")
+	if err := fs.Rename(path, renamed); err != nil {
+		t.Fatalf("Failed to Rename '%s' to '%s': %v", path, renamed, err)
+	}
+	if exists, err := Exists(fs, path); err != nil {
+		t.Fatalf("Failed to stat '%s': %v", path, err)
+	} else if exists {
+		t.Fatalf("Expected '%s' not to exist after rename", path)
+	}
+
+	var walked []string
+	if err := fs.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			walked = append(walked, p)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to walk '%s': %v", dir, err)
+	}
+	if len(walked) != 1 || walked[0] != renamed {
+		t.Fatalf("Expected to walk only '%s', got %v", renamed, walked)
+	}
+
+	if err := fs.RemoveAll(filepath.Join(dir, "a")); err != nil {
+		t.Fatalf("Failed to RemoveAll '%s': %v", filepath.Join(dir, "a"), err)
+	}
+	if exists, err := Exists(fs, renamed); err != nil {
+		t.Fatalf("Failed to stat '%s': %v", renamed, err)
+	} else if exists {
+		t.Fatalf("Expected '%s' to be removed", renamed)
+	}
+}
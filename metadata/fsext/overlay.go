@@ -0,0 +1,101 @@
+package fsext
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Overlay composes two FSes: reads are served by primary, falling through
+// to secondary when primary doesn't have the path; writes always go to
+// primary. This lets a curated, vendored tree (secondary) sit underneath a
+// user-supplied directory (primary) without ever being mutated.
+type Overlay struct {
+	primary   FS
+	secondary FS
+}
+
+// NewOverlay returns an FS that prefers primary, falling through to
+// secondary for reads primary doesn't satisfy.
+func NewOverlay(primary, secondary FS) *Overlay {
+	return &Overlay{primary: primary, secondary: secondary}
+}
+
+// ReadFile implements FS.
+func (o *Overlay) ReadFile(path string) ([]byte, error) {
+	data, err := o.primary.ReadFile(path)
+	if isNotExist(err) {
+		return o.secondary.ReadFile(path)
+	}
+	return data, err
+}
+
+// Stat implements FS.
+func (o *Overlay) Stat(path string) (os.FileInfo, error) {
+	info, err := o.primary.Stat(path)
+	if isNotExist(err) {
+		return o.secondary.Stat(path)
+	}
+	return info, err
+}
+
+// OpenFile implements FS. Writes (anything but a pure read) always target
+// primary; reads fall through to secondary if primary doesn't have path.
+func (o *Overlay) OpenFile(path string, flag int, perm os.FileMode) (File, error) {
+	if flag != os.O_RDONLY {
+		return o.primary.OpenFile(path, flag, perm)
+	}
+
+	f, err := o.primary.OpenFile(path, flag, perm)
+	if isNotExist(err) {
+		return o.secondary.OpenFile(path, flag, perm)
+	}
+	return f, err
+}
+
+// Walk implements FS, visiting every path reachable through either layer
+// exactly once, preferring the primary's view of any path both layers share.
+func (o *Overlay) Walk(root string, walkFn filepath.WalkFunc) error {
+	seen := map[string]bool{}
+
+	err := o.primary.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil {
+			seen[path] = true
+		}
+		return walkFn(path, info, err)
+	})
+	if err != nil && !isNotExist(err) {
+		return err
+	}
+
+	err = o.secondary.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || seen[path] {
+			return nil
+		}
+		return walkFn(path, info, err)
+	})
+	if err != nil && !isNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// MkdirAll implements FS, always targeting primary.
+func (o *Overlay) MkdirAll(path string, perm os.FileMode) error {
+	return o.primary.MkdirAll(path, perm)
+}
+
+// WriteFile implements FS, always targeting primary.
+func (o *Overlay) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return o.primary.WriteFile(path, data, perm)
+}
+
+// RemoveAll implements FS, always targeting primary.
+func (o *Overlay) RemoveAll(path string) error {
+	return o.primary.RemoveAll(path)
+}
+
+// Rename implements FS, always targeting primary.
+func (o *Overlay) Rename(oldpath, newpath string) error {
+	return o.primary.Rename(oldpath, newpath)
+}
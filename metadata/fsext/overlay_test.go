@@ -0,0 +1,129 @@
+package fsext
+
+import (
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func newOverlayFixture() (primary, secondary afero.Fs, overlay *Overlay) {
+	primary = afero.NewMemMapFs()
+	secondary = afero.NewMemMapFs()
+	return primary, secondary, NewOverlay(NewAferoFS(primary), NewAferoFS(secondary))
+}
+
+func TestOverlayReadFallsThroughToSecondary(t *testing.T) {
+	primary, secondary, overlay := newOverlayFixture()
+
+	afero.WriteFile(secondary, "/only-secondary.txt", []byte("secondary"), os.ModePerm)
+	afero.WriteFile(primary, "/both.txt", []byte("primary"), os.ModePerm)
+	afero.WriteFile(secondary, "/both.txt", []byte("secondary"), os.ModePerm)
+
+	data, err := overlay.ReadFile("/only-secondary.txt")
+	if err != nil {
+		t.Fatalf("Failed to read '/only-secondary.txt' through overlay: %v", err)
+	} else if string(data) != "secondary" {
+		t.Fatalf("Expected overlay to read through to secondary, got '%s'", data)
+	}
+
+	data, err = overlay.ReadFile("/both.txt")
+	if err != nil {
+		t.Fatalf("Failed to read '/both.txt' through overlay: %v", err)
+	} else if string(data) != "primary" {
+		t.Fatalf("Expected overlay to prefer primary's copy of '/both.txt', got '%s'", data)
+	}
+
+	if _, err := overlay.ReadFile("/missing.txt"); !isNotExist(err) {
+		t.Fatalf("Expected a not-exist error reading '/missing.txt', got %v", err)
+	}
+}
+
+func TestOverlayStatFallsThroughToSecondary(t *testing.T) {
+	_, secondary, overlay := newOverlayFixture()
+	afero.WriteFile(secondary, "/only-secondary.txt", []byte("secondary"), os.ModePerm)
+
+	info, err := overlay.Stat("/only-secondary.txt")
+	if err != nil {
+		t.Fatalf("Failed to stat '/only-secondary.txt' through overlay: %v", err)
+	} else if info.Name() != "only-secondary.txt" {
+		t.Fatalf("Expected to stat 'only-secondary.txt', got '%s'", info.Name())
+	}
+
+	if _, err := overlay.Stat("/missing.txt"); !isNotExist(err) {
+		t.Fatalf("Expected a not-exist error statting '/missing.txt', got %v", err)
+	}
+}
+
+func TestOverlayWritesTargetPrimary(t *testing.T) {
+	primary, secondary, overlay := newOverlayFixture()
+
+	if err := overlay.MkdirAll("/a/b", 0755); err != nil {
+		t.Fatalf("Failed to MkdirAll through overlay: %v", err)
+	}
+	if exists, _ := afero.DirExists(primary, "/a/b"); !exists {
+		t.Fatalf("Expected MkdirAll to create '/a/b' in primary")
+	}
+	if exists, _ := afero.DirExists(secondary, "/a/b"); exists {
+		t.Fatalf("Expected MkdirAll not to touch secondary")
+	}
+
+	if err := overlay.WriteFile("/a/b/f.txt", []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to WriteFile through overlay: %v", err)
+	}
+	if exists, _ := afero.Exists(primary, "/a/b/f.txt"); !exists {
+		t.Fatalf("Expected WriteFile to write to primary")
+	}
+	if exists, _ := afero.Exists(secondary, "/a/b/f.txt"); exists {
+		t.Fatalf("Expected WriteFile not to touch secondary")
+	}
+
+	if err := overlay.Rename("/a/b/f.txt", "/a/b/g.txt"); err != nil {
+		t.Fatalf("Failed to Rename through overlay: %v", err)
+	}
+	if exists, _ := afero.Exists(primary, "/a/b/g.txt"); !exists {
+		t.Fatalf("Expected Rename to rename within primary")
+	}
+
+	if err := overlay.RemoveAll("/a"); err != nil {
+		t.Fatalf("Failed to RemoveAll through overlay: %v", err)
+	}
+	if exists, _ := afero.DirExists(primary, "/a"); exists {
+		t.Fatalf("Expected RemoveAll to remove '/a' from primary")
+	}
+}
+
+func TestOverlayWalkMergesBothLayers(t *testing.T) {
+	primary, secondary, overlay := newOverlayFixture()
+
+	afero.WriteFile(primary, "/root/primary-only.txt", []byte{}, os.ModePerm)
+	afero.WriteFile(primary, "/root/both.txt", []byte("primary"), os.ModePerm)
+	afero.WriteFile(secondary, "/root/both.txt", []byte("secondary"), os.ModePerm)
+	afero.WriteFile(secondary, "/root/secondary-only.txt", []byte{}, os.ModePerm)
+
+	var seen []string
+	err := overlay.Walk("/root", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			seen = append(seen, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk overlay: %v", err)
+	}
+	sort.Strings(seen)
+
+	want := []string{"/root/both.txt", "/root/primary-only.txt", "/root/secondary-only.txt"}
+	if len(seen) != len(want) {
+		t.Fatalf("Expected to walk %v, got %v", want, seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("Expected to walk %v, got %v", want, seen)
+		}
+	}
+}
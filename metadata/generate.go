@@ -0,0 +1,18 @@
+package metadata
+
+import (
+	"github.com/pkg/errors"
+
+	ksonnetgen "github.com/ksonnet/ksonnet-lib/ksonnet-gen/ksonnet"
+)
+
+// generateLibs renders the `k8sLibFilename` and `extensionsLibFilename`
+// jsonnet sources for version from the raw OpenAPI spec in specData.
+func generateLibs(specData []byte, version string) (k8sLib, extensionsLib string, err error) {
+	k8sLib, extensionsLib, err = ksonnetgen.Emit(specData, version)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Could not generate ksonnet-lib")
+	}
+
+	return k8sLib, extensionsLib, nil
+}
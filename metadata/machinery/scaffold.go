@@ -0,0 +1,159 @@
+// Package machinery provides the transactional, template-driven scaffolding
+// ksonnet uses to write a set of files and directories to an FS in one
+// all-or-nothing operation. `initManager` uses it to lay out a new
+// application; future prototype generators (e.g. `ks prototype use
+// nginx-deployment`) can reuse the same Template/Scaffold pair to generate
+// component files.
+package machinery
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/chemist/ksonnet/metadata/fsext"
+)
+
+const (
+	stagingSuffix            = ".scaffold-staging"
+	defaultFolderPermissions = 0755
+	defaultFilePermissions   = 0644
+)
+
+// IfExistsAction describes what a Scaffold should do when a Template's Path
+// already exists on disk.
+type IfExistsAction int
+
+const (
+	// IfExistsError fails the scaffold if Path already exists.
+	IfExistsError IfExistsAction = iota
+	// IfExistsSkip leaves an existing Path untouched.
+	IfExistsSkip
+	// IfExistsOverwrite replaces an existing Path with Contents.
+	IfExistsOverwrite
+)
+
+// Template describes a single file or directory a Scaffold should write.
+// Contents is ignored when IsDir is true.
+type Template struct {
+	Path           string
+	Contents       []byte
+	IsDir          bool
+	IfExistsAction IfExistsAction
+}
+
+// pendingTemplate is a Template that has been staged and is ready to be
+// moved into its final location.
+type pendingTemplate struct {
+	stagingPath string
+	path        string
+	isDir       bool
+}
+
+// Scaffold applies a set of Templates to an FS transactionally: every
+// template is first written under a staging directory; once all of them
+// have staged successfully, they're moved into place one by one, rolling
+// back any that already landed if a later move fails. Either every
+// template ends up on the real FS, or none of them do.
+type Scaffold struct{}
+
+// Execute applies templates to fs, all rooted under root. Every path in
+// templates must be root or a descendant of it.
+func (Scaffold) Execute(fs fsext.FS, root string, templates ...Template) error {
+	stagingRoot := root + stagingSuffix
+	if err := fs.RemoveAll(stagingRoot); err != nil {
+		return errors.Wrapf(err, "Could not clear staging directory '%s'", stagingRoot)
+	}
+	defer fs.RemoveAll(stagingRoot)
+
+	var toCommit []pendingTemplate
+
+	for _, tmpl := range templates {
+		rel, err := filepath.Rel(root, tmpl.Path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("Template path '%s' is not under root '%s'", tmpl.Path, root)
+		}
+
+		exists, err := fsext.Exists(fs, tmpl.Path)
+		if err != nil {
+			return err
+		}
+		if exists {
+			switch tmpl.IfExistsAction {
+			case IfExistsSkip:
+				continue
+			case IfExistsError:
+				return fmt.Errorf("'%s' already exists", tmpl.Path)
+			case IfExistsOverwrite:
+				// Fall through; the staged replacement below wins on commit.
+			}
+		}
+
+		stagingPath := filepath.Join(stagingRoot, rel)
+		if tmpl.IsDir {
+			if err := fs.MkdirAll(stagingPath, defaultFolderPermissions); err != nil {
+				return errors.Wrapf(err, "Could not stage directory '%s'", tmpl.Path)
+			}
+		} else {
+			if err := fs.MkdirAll(filepath.Dir(stagingPath), defaultFolderPermissions); err != nil {
+				return errors.Wrapf(err, "Could not stage '%s'", tmpl.Path)
+			}
+			if err := fs.WriteFile(stagingPath, tmpl.Contents, defaultFilePermissions); err != nil {
+				return errors.Wrapf(err, "Could not stage '%s'", tmpl.Path)
+			}
+		}
+
+		toCommit = append(toCommit, pendingTemplate{stagingPath: stagingPath, path: tmpl.Path, isDir: tmpl.IsDir})
+	}
+
+	var committed []pendingTemplate
+	for _, p := range toCommit {
+		ok, err := commitOne(fs, p)
+		if err != nil {
+			// Roll back everything this Execute call already moved into
+			// place, in reverse order, so a failure partway through the
+			// commit never leaves a half-scaffolded tree behind.
+			for i := len(committed) - 1; i >= 0; i-- {
+				fs.RemoveAll(committed[i].path)
+			}
+			return err
+		}
+		if ok {
+			committed = append(committed, p)
+		}
+	}
+
+	return nil
+}
+
+// commitOne puts a single staged template into its final location: a file
+// is moved there from staging, and a directory is created there (renaming
+// the staged directory would also carry along staged descendants, which are
+// committed as templates of their own). The returned bool reports whether
+// anything was actually put in place (and so needs to be rolled back on a
+// later failure).
+func commitOne(fs fsext.FS, p pendingTemplate) (bool, error) {
+	if err := fs.MkdirAll(filepath.Dir(p.path), defaultFolderPermissions); err != nil {
+		return false, errors.Wrapf(err, "Could not create '%s'", filepath.Dir(p.path))
+	}
+	if p.isDir {
+		if exists, err := fsext.DirExists(fs, p.path); err != nil {
+			return false, err
+		} else if exists {
+			// An IfExistsOverwrite directory that already exists is already
+			// in the right shape; nothing to move into place, and nothing
+			// to roll back if a later template fails.
+			return false, nil
+		}
+		if err := fs.MkdirAll(p.path, defaultFolderPermissions); err != nil {
+			return false, errors.Wrapf(err, "Could not create '%s'", p.path)
+		}
+		return true, nil
+	}
+	if err := fs.Rename(p.stagingPath, p.path); err != nil {
+		return false, errors.Wrapf(err, "Could not move staged '%s' into place", p.path)
+	}
+	return true, nil
+}
@@ -0,0 +1,174 @@
+package machinery
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/chemist/ksonnet/metadata/fsext"
+)
+
+// failOnWriteFS wraps an FS and fails the write to exactly one path, so
+// tests can exercise a scaffold that fails partway through.
+type failOnWriteFS struct {
+	fsext.FS
+	failPath string
+}
+
+func (f failOnWriteFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	if path == f.failPath {
+		return fmt.Errorf("simulated write failure for '%s'", path)
+	}
+	return f.FS.WriteFile(path, data, perm)
+}
+
+func TestScaffoldTransactionalRollback(t *testing.T) {
+	fs := failOnWriteFS{
+		FS:       fsext.NewAferoFS(afero.NewMemMapFs()),
+		failPath: "/app.scaffold-staging/b.txt",
+	}
+
+	templates := []Template{
+		{Path: "/app/a.txt", Contents: []byte("a"), IfExistsAction: IfExistsError},
+		{Path: "/app/b.txt", Contents: []byte("b"), IfExistsAction: IfExistsError},
+		{Path: "/app/c.txt", Contents: []byte("c"), IfExistsAction: IfExistsError},
+	}
+
+	s := Scaffold{}
+	if err := s.Execute(fs, "/app", templates...); err == nil {
+		t.Fatalf("Expected scaffold to fail when one template's write fails")
+	}
+
+	for _, path := range []string{"/app/a.txt", "/app/b.txt", "/app/c.txt"} {
+		if exists, err := fsext.Exists(fs, path); err != nil {
+			t.Fatalf("Failed to stat '%s': %v", path, err)
+		} else if exists {
+			t.Fatalf("Expected '%s' not to exist after a rolled-back scaffold", path)
+		}
+	}
+
+	if exists, err := fsext.Exists(fs, "/app.scaffold-staging"); err != nil {
+		t.Fatalf("Failed to stat staging directory: %v", err)
+	} else if exists {
+		t.Fatalf("Expected staging directory to be cleaned up after rollback")
+	}
+}
+
+// failOnRenameFS wraps an FS and fails the Rename of exactly one path, so
+// tests can exercise a scaffold that fails partway through the commit
+// phase, after staging has already succeeded for every template.
+type failOnRenameFS struct {
+	fsext.FS
+	failPath string
+}
+
+func (f failOnRenameFS) Rename(oldPath, newPath string) error {
+	if newPath == f.failPath {
+		return fmt.Errorf("simulated rename failure for '%s'", newPath)
+	}
+	return f.FS.Rename(oldPath, newPath)
+}
+
+func TestScaffoldCommitPhaseRollback(t *testing.T) {
+	fs := failOnRenameFS{
+		FS:       fsext.NewAferoFS(afero.NewMemMapFs()),
+		failPath: "/app/c.txt",
+	}
+
+	templates := []Template{
+		{Path: "/app/a.txt", Contents: []byte("a"), IfExistsAction: IfExistsError},
+		{Path: "/app/b.txt", Contents: []byte("b"), IfExistsAction: IfExistsError},
+		{Path: "/app/c.txt", Contents: []byte("c"), IfExistsAction: IfExistsError},
+	}
+
+	s := Scaffold{}
+	if err := s.Execute(fs, "/app", templates...); err == nil {
+		t.Fatalf("Expected scaffold to fail when one template's commit-phase rename fails")
+	}
+
+	// a.txt and b.txt were already renamed into place by the time c.txt's
+	// rename failed; Execute must have rolled them back rather than leaving
+	// a half-scaffolded tree.
+	for _, path := range []string{"/app/a.txt", "/app/b.txt", "/app/c.txt"} {
+		if exists, err := fsext.Exists(fs, path); err != nil {
+			t.Fatalf("Failed to stat '%s': %v", path, err)
+		} else if exists {
+			t.Fatalf("Expected '%s' not to exist after a rolled-back commit phase", path)
+		}
+	}
+}
+
+func TestScaffoldIfExistsMatrix(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     IfExistsAction
+		isDir      bool
+		preExecute bool
+		wantErr    bool
+		wantResult string
+	}{
+		{name: "error on existing file", action: IfExistsError, wantErr: true},
+		{name: "error on existing directory", action: IfExistsError, isDir: true, wantErr: true},
+		// This is the scenario TestDoubleNewFailure used to cover on its own:
+		// running Scaffold.Execute a second time against a directory it
+		// already created (as initManager does) must fail the same way as
+		// running it against a directory created out-of-band.
+		{name: "error re-executing against an already-scaffolded directory", action: IfExistsError, isDir: true, preExecute: true, wantErr: true},
+		{name: "skip leaves existing file untouched", action: IfExistsSkip, wantResult: "original"},
+		{name: "overwrite replaces existing file", action: IfExistsOverwrite, wantResult: "replacement"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsext.NewAferoFS(afero.NewMemMapFs())
+
+			path := "/app/thing"
+			tmpl := Template{
+				Path:           path,
+				Contents:       []byte("replacement"),
+				IsDir:          tt.isDir,
+				IfExistsAction: tt.action,
+			}
+
+			s := Scaffold{}
+			if tt.preExecute {
+				if err := s.Execute(fs, "/app", Template{Path: path, IsDir: tt.isDir, IfExistsAction: IfExistsError}); err != nil {
+					t.Fatalf("Failed to pre-scaffold '%s': %v", path, err)
+				}
+			} else if tt.isDir {
+				if err := fs.MkdirAll(path, defaultFolderPermissions); err != nil {
+					t.Fatalf("Failed to pre-create directory: %v", err)
+				}
+			} else {
+				if err := fs.WriteFile(path, []byte("original"), defaultFilePermissions); err != nil {
+					t.Fatalf("Failed to pre-create file: %v", err)
+				}
+			}
+
+			err := s.Execute(fs, "/app", tmpl)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected scaffold to fail for an existing path with IfExistsError")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected scaffold to succeed: %v", err)
+			}
+
+			if tt.isDir {
+				return
+			}
+
+			data, err := fs.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read '%s': %v", path, err)
+			} else if string(data) != tt.wantResult {
+				t.Fatalf("Expected '%s' to contain '%s', got '%s'", path, tt.wantResult, data)
+			}
+		})
+	}
+}
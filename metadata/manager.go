@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/chemist/ksonnet/metadata/fsext"
+	"github.com/chemist/ksonnet/metadata/machinery"
+)
+
+const (
+	ksonnetDir            = ".ksonnet"
+	libDir                = "lib"
+	componentsDir         = "components"
+	environmentsDir       = "environments"
+	vendorDir             = "vendor"
+	defaultEnvName        = "default"
+	schemaFilename        = "swagger.json"
+	k8sLibFilename        = "k8s.libsonnet"
+	extensionsLibFilename = "k.libsonnet"
+
+	currentEnvironmentFilename = "current-environment"
+
+	defaultFolderPermissions = os.FileMode(0755)
+	defaultFilePermissions   = os.FileMode(0644)
+)
+
+// AbsPath is an absolute path to a file or directory belonging to a ksonnet
+// application.
+type AbsPath string
+
+// appendToAbsPath joins an additional path component onto an AbsPath.
+func appendToAbsPath(path AbsPath, part string) AbsPath {
+	return AbsPath(filepath.Join(string(path), part))
+}
+
+// manager resolves and mutates the on-disk layout of a single ksonnet
+// application: its root directory, vendored library, and environments.
+type manager struct {
+	rootPath AbsPath
+	fs       fsext.FS
+}
+
+// initManager scaffolds a new ksonnet application rooted at appPath,
+// creating its directory layout and a `defaultEnvName` environment built
+// from the given cluster spec.
+func initManager(appPath AbsPath, spec *ClusterSpec, fs fsext.FS) (*manager, error) {
+	exists, err := fsext.DirExists(fs, string(appPath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not check existence of directory '%s'", appPath)
+	} else if exists {
+		return nil, fmt.Errorf("Could not create app; directory '%s' already exists", appPath)
+	}
+
+	m := &manager{rootPath: appPath, fs: fs}
+
+	var templates []machinery.Template
+	for _, dir := range []string{ksonnetDir, libDir, componentsDir, environmentsDir, vendorDir} {
+		templates = append(templates, machinery.Template{
+			Path:           string(appendToAbsPath(appPath, dir)),
+			IsDir:          true,
+			IfExistsAction: machinery.IfExistsError,
+		})
+	}
+
+	scaffold := machinery.Scaffold{}
+	if err := scaffold.Execute(fs, string(appPath), templates...); err != nil {
+		return nil, errors.Wrapf(err, "Could not scaffold app directory layout")
+	}
+
+	if err := m.CreateEnvironment(defaultEnvName, "", "", spec); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// findManager walks up from path looking for the ksonnetDir marker that
+// identifies the root of a ksonnet application.
+func findManager(path AbsPath, fs fsext.FS) (*manager, error) {
+	if path == "" {
+		return nil, fmt.Errorf("Could not find ksonnet app root in '%s'", path)
+	}
+
+	dir := path
+	if isDir, err := fsext.IsDir(fs, string(path)); err != nil {
+		return nil, errors.Wrapf(err, "Could not stat '%s'", path)
+	} else if !isDir {
+		dir = AbsPath(filepath.Dir(string(path)))
+	}
+
+	for {
+		marker := appendToAbsPath(dir, ksonnetDir)
+		exists, err := fsext.DirExists(fs, string(marker))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not stat '%s'", marker)
+		} else if exists {
+			return &manager{rootPath: dir, fs: fs}, nil
+		}
+
+		parent := filepath.Dir(string(dir))
+		if parent == string(dir) {
+			break
+		}
+		dir = AbsPath(parent)
+	}
+
+	return nil, fmt.Errorf("Could not find ksonnet app root in '%s'", path)
+}
+
+// ComponentPaths returns the absolute paths of every component source file
+// under the application's componentsDir, recursing into subdirectories.
+func (m *manager) ComponentPaths() ([]string, error) {
+	componentsPath := appendToAbsPath(m.rootPath, componentsDir)
+
+	var paths []string
+	err := m.fs.Walk(string(componentsPath), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if info.IsDir() {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not walk components directory '%s'", componentsPath)
+	}
+
+	return paths, nil
+}
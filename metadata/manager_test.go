@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/spf13/afero"
+
+	"github.com/chemist/ksonnet/metadata/fsext"
 )
 
 const (
@@ -33,19 +35,20 @@ const (
 )
 
 var testFS = afero.NewMemMapFs()
+var testMetadataFS = fsext.NewAferoFS(testFS)
 
 func init() {
 	afero.WriteFile(testFS, blankSwagger, []byte(blankSwaggerData), os.ModePerm)
 }
 
 func TestInitSuccess(t *testing.T) {
-	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testFS)
+	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testMetadataFS)
 	if err != nil {
 		t.Fatalf("Failed to parse cluster spec: %v", err)
 	}
 
 	appPath := AbsPath("/fromEmptySwagger")
-	_, err = initManager(appPath, spec, testFS)
+	_, err = initManager(appPath, spec, testMetadataFS)
 	if err != nil {
 		t.Fatalf("Failed to init cluster spec: %v", err)
 	}
@@ -98,7 +101,7 @@ func TestInitSuccess(t *testing.T) {
 
 func TestFindSuccess(t *testing.T) {
 	findSuccess := func(t *testing.T, appDir, currDir AbsPath) {
-		m, err := findManager(currDir, testFS)
+		m, err := findManager(currDir, testMetadataFS)
 		if err != nil {
 			t.Fatalf("Failed to find manager at path '%s':\n%v", currDir, err)
 		} else if m.rootPath != appDir {
@@ -106,13 +109,13 @@ func TestFindSuccess(t *testing.T) {
 		}
 	}
 
-	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testFS)
+	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testMetadataFS)
 	if err != nil {
 		t.Fatalf("Failed to parse cluster spec: %v", err)
 	}
 
 	appPath := AbsPath("/findSuccess")
-	_, err = initManager(appPath, spec, testFS)
+	_, err = initManager(appPath, spec, testMetadataFS)
 	if err != nil {
 		t.Fatalf("Failed to init cluster spec: %v", err)
 	}
@@ -134,13 +137,13 @@ func TestFindSuccess(t *testing.T) {
 }
 
 func TestComponentPaths(t *testing.T) {
-	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testFS)
+	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testMetadataFS)
 	if err != nil {
 		t.Fatalf("Failed to parse cluster spec: %v", err)
 	}
 
 	appPath := AbsPath("/componentPaths")
-	m, err := initManager(appPath, spec, testFS)
+	m, err := initManager(appPath, spec, testMetadataFS)
 	if err != nil {
 		t.Fatalf("Failed to init cluster spec: %v", err)
 	}
@@ -188,7 +191,7 @@ func TestComponentPaths(t *testing.T) {
 
 func TestFindFailure(t *testing.T) {
 	findFailure := func(t *testing.T, currDir AbsPath) {
-		_, err := findManager(currDir, testFS)
+		_, err := findManager(currDir, testMetadataFS)
 		if err == nil {
 			t.Fatalf("Expected to fail to find ksonnet app in '%s', but succeeded", currDir)
 		}
@@ -199,22 +202,49 @@ func TestFindFailure(t *testing.T) {
 	findFailure(t, "")
 }
 
-func TestDoubleNewFailure(t *testing.T) {
-	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), testFS)
+func TestComponentPathsOverlay(t *testing.T) {
+	primary := afero.NewMemMapFs()
+	secondary := afero.NewMemMapFs()
+	afero.WriteFile(secondary, blankSwagger, []byte(blankSwaggerData), os.ModePerm)
+
+	overlay := fsext.NewOverlay(fsext.NewAferoFS(primary), fsext.NewAferoFS(secondary))
+
+	spec, err := parseClusterSpec(fmt.Sprintf("file:%s", blankSwagger), overlay)
 	if err != nil {
 		t.Fatalf("Failed to parse cluster spec: %v", err)
 	}
 
-	appPath := AbsPath("/doubleNew")
-
-	_, err = initManager(appPath, spec, testFS)
+	appPath := AbsPath("/overlayComponents")
+	m, err := initManager(appPath, spec, overlay)
 	if err != nil {
 		t.Fatalf("Failed to init cluster spec: %v", err)
 	}
 
-	targetErr := fmt.Sprintf("Could not create app; directory '%s' already exists", appPath)
-	_, err = initManager(appPath, spec, testFS)
-	if err == nil || err.Error() != targetErr {
-		t.Fatalf("Expected to fail to create app with message '%s', got '%s'", targetErr, err.Error())
+	// The app directory only exists in the primary layer, but initManager
+	// must still refuse to re-scaffold on top of it.
+	if _, err := initManager(appPath, spec, overlay); err == nil {
+		t.Fatalf("Expected re-initializing an existing app through an overlay to fail")
+	}
+
+	components := appendToAbsPath(appPath, componentsDir)
+
+	primaryFile := appendToAbsPath(components, "primary.jsonnet")
+	if err := afero.WriteFile(primary, string(primaryFile), []byte{}, os.ModePerm); err != nil {
+		t.Fatalf("Failed to write '%s': %v", primaryFile, err)
+	}
+
+	secondaryFile := appendToAbsPath(components, "secondary.jsonnet")
+	if err := afero.WriteFile(secondary, string(secondaryFile), []byte{}, os.ModePerm); err != nil {
+		t.Fatalf("Failed to write '%s': %v", secondaryFile, err)
+	}
+
+	paths, err := m.ComponentPaths()
+	if err != nil {
+		t.Fatalf("Failed to find component paths: %v", err)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+
+	if len(paths) != 2 || paths[0] != string(primaryFile) || paths[1] != string(secondaryFile) {
+		t.Fatalf("Expected overlay ComponentPaths to see both layers, got '%v'", paths)
 	}
 }